@@ -0,0 +1,129 @@
+// Copyright 2022
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/viper"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	otelmetric "go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/sdk/metric"
+)
+
+// startOtlpExporter bridges reg to an OpenTelemetry collector over
+// OTLP/gRPC: it gathers reg once to learn the fixed set of metric names
+// pvdb-metrics produces, registers one observable gauge per name, and
+// registers a callback that re-gathers reg on every collect and reports
+// its samples through those gauges. The returned func shuts the exporter
+// down, flushing any pending export and closing the gRPC connection.
+func startOtlpExporter(ctx context.Context, reg prometheus.Gatherer) (func(context.Context) error, error) {
+	endpoint := viper.GetString("otlp.endpoint")
+	if endpoint == "" {
+		return nil, fmt.Errorf("otlp mode enabled but otlp.endpoint is not set")
+	}
+
+	exporter, err := otlpmetricgrpc.New(ctx, otlpmetricgrpc.WithEndpoint(endpoint), otlpmetricgrpc.WithInsecure())
+	if err != nil {
+		return nil, err
+	}
+
+	provider := metric.NewMeterProvider(metric.WithReader(
+		metric.NewPeriodicReader(exporter, metric.WithInterval(30*time.Second)),
+	))
+
+	families, err := reg.Gather()
+	if err != nil {
+		return nil, fmt.Errorf("failed to gather metrics for OTLP bridge setup: %w", err)
+	}
+
+	meter := provider.Meter("github.com/penny-vault/pvdb-metrics")
+	gauges := make(map[string]otelmetric.Float64ObservableGauge, len(families))
+	instruments := make([]otelmetric.Observable, 0, len(families))
+	for _, mf := range families {
+		gauge, err := meter.Float64ObservableGauge(mf.GetName(), otelmetric.WithDescription(mf.GetHelp()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create OTLP instrument for %s: %w", mf.GetName(), err)
+		}
+		gauges[mf.GetName()] = gauge
+		instruments = append(instruments, gauge)
+	}
+
+	_, err = meter.RegisterCallback(func(_ context.Context, o otelmetric.Observer) error {
+		families, err := reg.Gather()
+		if err != nil {
+			return err
+		}
+
+		for _, mf := range families {
+			gauge, ok := gauges[mf.GetName()]
+			if !ok {
+				// A metric that didn't exist when the bridge started up;
+				// it will be picked up the next time the process restarts.
+				continue
+			}
+
+			for _, m := range mf.GetMetric() {
+				value, ok := prometheusMetricValue(mf.GetType(), m)
+				if !ok {
+					continue
+				}
+				o.ObserveFloat64(gauge, value, otelmetric.WithAttributes(prometheusLabelsToAttributes(m.GetLabel())...))
+			}
+		}
+
+		return nil
+	}, instruments...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to register OTLP bridge callback: %w", err)
+	}
+
+	log.Info().Str("endpoint", endpoint).Int("metrics", len(gauges)).Msg("exporting metrics via OTLP")
+
+	return provider.Shutdown, nil
+}
+
+// prometheusMetricValue extracts the single float64 sample OTLP should
+// report for m, based on the Prometheus metric type it was gathered as.
+func prometheusMetricValue(t dto.MetricType, m *dto.Metric) (float64, bool) {
+	switch t {
+	case dto.MetricType_GAUGE:
+		return m.GetGauge().GetValue(), true
+	case dto.MetricType_COUNTER:
+		return m.GetCounter().GetValue(), true
+	case dto.MetricType_HISTOGRAM:
+		return m.GetHistogram().GetSampleSum(), true
+	case dto.MetricType_UNTYPED:
+		return m.GetUntyped().GetValue(), true
+	default:
+		return 0, false
+	}
+}
+
+// prometheusLabelsToAttributes converts a gathered metric's label pairs
+// into OTel attributes.
+func prometheusLabelsToAttributes(labels []*dto.LabelPair) []attribute.KeyValue {
+	attrs := make([]attribute.KeyValue, 0, len(labels))
+	for _, l := range labels {
+		attrs = append(attrs, attribute.String(l.GetName(), l.GetValue()))
+	}
+	return attrs
+}
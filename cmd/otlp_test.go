@@ -0,0 +1,107 @@
+// Copyright 2022
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"testing"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestPrometheusMetricValue(t *testing.T) {
+	tests := []struct {
+		name   string
+		mType  dto.MetricType
+		metric *dto.Metric
+		want   float64
+		wantOk bool
+	}{
+		{
+			name:   "gauge",
+			mType:  dto.MetricType_GAUGE,
+			metric: &dto.Metric{Gauge: &dto.Gauge{Value: floatPtr(3.5)}},
+			want:   3.5,
+			wantOk: true,
+		},
+		{
+			name:   "counter",
+			mType:  dto.MetricType_COUNTER,
+			metric: &dto.Metric{Counter: &dto.Counter{Value: floatPtr(42)}},
+			want:   42,
+			wantOk: true,
+		},
+		{
+			name:   "histogram reports its sample sum",
+			mType:  dto.MetricType_HISTOGRAM,
+			metric: &dto.Metric{Histogram: &dto.Histogram{SampleSum: floatPtr(7.25)}},
+			want:   7.25,
+			wantOk: true,
+		},
+		{
+			name:   "untyped",
+			mType:  dto.MetricType_UNTYPED,
+			metric: &dto.Metric{Untyped: &dto.Untyped{Value: floatPtr(1)}},
+			want:   1,
+			wantOk: true,
+		},
+		{
+			name:   "summary is not supported",
+			mType:  dto.MetricType_SUMMARY,
+			metric: &dto.Metric{Summary: &dto.Summary{SampleSum: floatPtr(9)}},
+			wantOk: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := prometheusMetricValue(tt.mType, tt.metric)
+			if ok != tt.wantOk {
+				t.Fatalf("prometheusMetricValue() ok = %v, want %v", ok, tt.wantOk)
+			}
+			if ok && got != tt.want {
+				t.Errorf("prometheusMetricValue() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrometheusLabelsToAttributes(t *testing.T) {
+	labels := []*dto.LabelPair{
+		{Name: strPtr("database"), Value: strPtr("prod")},
+		{Name: strPtr("metric"), Value: strPtr("pvdb_eod_daily")},
+	}
+
+	attrs := prometheusLabelsToAttributes(labels)
+	if len(attrs) != 2 {
+		t.Fatalf("prometheusLabelsToAttributes() returned %d attributes, want 2", len(attrs))
+	}
+	if string(attrs[0].Key) != "database" || attrs[0].Value.AsString() != "prod" {
+		t.Errorf("attrs[0] = %+v, want database=prod", attrs[0])
+	}
+	if string(attrs[1].Key) != "metric" || attrs[1].Value.AsString() != "pvdb_eod_daily" {
+		t.Errorf("attrs[1] = %+v, want metric=pvdb_eod_daily", attrs[1])
+	}
+}
+
+func TestPrometheusLabelsToAttributesEmpty(t *testing.T) {
+	attrs := prometheusLabelsToAttributes(nil)
+	if len(attrs) != 0 {
+		t.Errorf("prometheusLabelsToAttributes(nil) = %v, want empty", attrs)
+	}
+}
+
+func floatPtr(v float64) *float64 { return &v }
+
+func strPtr(v string) *string { return &v }
@@ -19,11 +19,16 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/jackc/pgx/v4/pgxpool"
 	"github.com/penny-vault/pvdb-metrics/metrics"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/client_golang/prometheus/push"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
@@ -63,35 +68,258 @@ func (c CronLog) Error(err error, msg string, keysAndValues ...interface{}) {
 	keysAndValuesToEvent(log.Error(), keysAndValues).Err(err).Msg(msg)
 }
 
+// deliveryModes are the values accepted by the --mode flag. Multiple modes
+// may run concurrently, e.g. "pull,push" or the "all" shorthand.
+const (
+	modePull = "pull"
+	modePush = "push"
+	modeOtlp = "otlp"
+	modeAll  = "all"
+)
+
+// enabledModes parses the comma-separated --mode flag into a set of modes.
+func enabledModes() map[string]bool {
+	modes := map[string]bool{}
+	for _, m := range strings.Split(viper.GetString("mode"), ",") {
+		m = strings.TrimSpace(strings.ToLower(m))
+		if m == "" {
+			continue
+		}
+		if m == modeAll {
+			modes[modePull] = true
+			modes[modePush] = true
+			modes[modeOtlp] = true
+			continue
+		}
+		modes[m] = true
+	}
+	return modes
+}
+
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
 	Use:   "pvdb-metrics",
 	Short: "run prometheus metrics collection for pvdb",
 	Run: func(cmd *cobra.Command, args []string) {
+		ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		defer stop()
+
 		reg := prometheus.NewRegistry()
 
-		pool, err := pgxpool.Connect(context.Background(), viper.GetString("database.url"))
+		dbConfigs, err := metrics.LoadDatabaseConfigs(viper.GetViper())
+		if err != nil {
+			log.Error().Err(err).Msg("failed to load databases config")
+		}
+
+		pools := make(map[string]*pgxpool.Pool, len(dbConfigs))
+		for _, dbConfig := range dbConfigs {
+			pool, err := metrics.Connect(ctx, dbConfig)
+			if err != nil {
+				log.Error().Err(err).Str("database", dbConfig.Name).Msg("failed to connect to database via pgxpool")
+				continue
+			}
+			pools[dbConfig.Name] = pool
+		}
+
+		// ctx also governs the collector's background cache-refresh
+		// goroutines, so they stop instead of querying pools that are about
+		// to be closed once a shutdown signal arrives.
+		collector, err := metrics.NewDbStatsCollector(ctx, pools)
 		if err != nil {
-			log.Error().Err(err).Msg("failed to connect to database via pgxpool")
+			log.Fatal().Err(err).Msg("failed to build metrics collector")
+		}
+		reg.MustRegister(collector)
+		reg.MustRegister(metrics.QueryDuration)
+		reg.MustRegister(metrics.CacheAge)
+		reg.MustRegister(metrics.ScrapeErrors)
+		reg.MustRegister(metrics.DatabaseUp)
+
+		modes := enabledModes()
+		if len(modes) == 0 {
+			modes[modePull] = true
 		}
-		defer pool.Close()
 
-		reg.MustRegister(metrics.NewDbStatsCollector(pool))
+		if modes[modePush] {
+			go runPushLoop(ctx, reg)
+		}
 
-		port := fmt.Sprintf(":%d", viper.GetInt("server.port"))
-		log.Info().Int("Port", viper.GetInt("server.port")).Msg("Starting HTTP server")
+		var otlpShutdown func(context.Context) error
+		if modes[modeOtlp] {
+			shutdown, err := startOtlpExporter(ctx, reg)
+			if err != nil {
+				log.Error().Err(err).Msg("failed to start OTLP exporter")
+			} else {
+				otlpShutdown = shutdown
+			}
+		}
 
-		http.Handle("/metrics", promhttp.HandlerFor(
-			reg,
-			promhttp.HandlerOpts{
-				// Opt into OpenMetrics to support exemplars.
-				EnableOpenMetrics: true,
-			},
-		))
-		http.ListenAndServe(port, nil)
+		metrics.StartLivenessLoop(ctx, pools, viper.GetDuration("database.ping_interval"))
+
+		if modes[modePull] {
+			runServer(ctx, reg, pools)
+		} else {
+			// Nothing left to do on the main goroutine other than keep the
+			// push/OTLP loops alive until a shutdown signal arrives.
+			<-ctx.Done()
+		}
+
+		if otlpShutdown != nil {
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			if err := otlpShutdown(shutdownCtx); err != nil {
+				log.Error().Err(err).Msg("error shutting down OTLP exporter")
+			}
+			cancel()
+		}
+
+		for name, pool := range pools {
+			log.Info().Str("database", name).Msg("closing database connection pool")
+			pool.Close()
+		}
 	},
 }
 
+// runServer builds the HTTP server exposing /metrics, /healthz and /readyz,
+// serves it (optionally over TLS), and blocks until ctx is cancelled, at
+// which point it drains in-flight requests within a configurable grace
+// period before returning.
+func runServer(ctx context.Context, reg prometheus.Gatherer, pools map[string]*pgxpool.Pool) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(
+		reg,
+		promhttp.HandlerOpts{
+			// Opt into OpenMetrics to support exemplars.
+			EnableOpenMetrics: true,
+		},
+	))
+	mux.HandleFunc("/healthz", healthzHandler)
+	mux.HandleFunc("/readyz", readyzHandler(toPingers(pools)))
+
+	srv := &http.Server{
+		Addr:    fmt.Sprintf(":%d", viper.GetInt("server.port")),
+		Handler: mux,
+	}
+
+	certFile := viper.GetString("server.tls.cert_file")
+	keyFile := viper.GetString("server.tls.key_file")
+
+	serveErr := make(chan error, 1)
+	go func() {
+		log.Info().Int("port", viper.GetInt("server.port")).Bool("tls", certFile != "" && keyFile != "").Msg("starting HTTP server")
+		if certFile != "" && keyFile != "" {
+			serveErr <- srv.ListenAndServeTLS(certFile, keyFile)
+		} else {
+			serveErr <- srv.ListenAndServe()
+		}
+	}()
+
+	select {
+	case err := <-serveErr:
+		if err != nil && err != http.ErrServerClosed {
+			log.Error().Err(err).Msg("HTTP server failed")
+		}
+		return
+	case <-ctx.Done():
+	}
+
+	grace := viper.GetDuration("server.shutdown_grace_period")
+	if grace <= 0 {
+		grace = 10 * time.Second
+	}
+
+	log.Info().Dur("grace_period", grace).Msg("shutting down HTTP server")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), grace)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Error().Err(err).Msg("error during HTTP server shutdown")
+	}
+}
+
+// healthzHandler reports liveness: if the process can answer HTTP at all, it
+// is alive, regardless of database connectivity.
+func healthzHandler(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintln(w, "ok")
+}
+
+// pinger is the subset of *pgxpool.Pool that readyzHandler depends on, so
+// tests can substitute a fake without a live Postgres connection.
+type pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// toPingers adapts a map of connection pools to the pinger interface
+// readyzHandler expects.
+func toPingers(pools map[string]*pgxpool.Pool) map[string]pinger {
+	pingers := make(map[string]pinger, len(pools))
+	for name, pool := range pools {
+		pingers[name] = pool
+	}
+	return pingers
+}
+
+// readyzHandler reports readiness: the process is ready only once every
+// pool in pools can be pinged.
+func readyzHandler(pools map[string]pinger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		pingCtx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+		defer cancel()
+
+		for name, pool := range pools {
+			if err := pool.Ping(pingCtx); err != nil {
+				log.Error().Err(err).Str("database", name).Msg("readiness check failed")
+				w.WriteHeader(http.StatusServiceUnavailable)
+				fmt.Fprintf(w, "not ready: database %q unreachable\n", name)
+				return
+			}
+		}
+
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintln(w, "ready")
+	}
+}
+
+// runPushLoop periodically pushes the contents of reg to a Prometheus
+// Pushgateway until ctx is cancelled. It is intended for short-lived,
+// cron-style invocations where an inbound scrape can't reliably land inside
+// the process lifetime.
+func runPushLoop(ctx context.Context, reg prometheus.Gatherer) {
+	url := viper.GetString("push.url")
+	if url == "" {
+		log.Error().Msg("push mode enabled but push.url is not set")
+		return
+	}
+
+	job := viper.GetString("push.job")
+	if job == "" {
+		job = "pvdb_metrics"
+	}
+
+	interval := viper.GetDuration("push.interval")
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+
+	pusher := push.New(url, job).Gatherer(reg)
+
+	doPush := func() {
+		if err := pusher.Push(); err != nil {
+			log.Error().Err(err).Str("url", url).Msg("failed to push metrics to pushgateway")
+		}
+	}
+
+	doPush()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			doPush()
+		}
+	}
+}
+
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
@@ -112,11 +340,37 @@ func init() {
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.pvdb-metrics.yaml)")
 	rootCmd.PersistentFlags().Bool("log.json", false, "print logs as json to stderr")
 	viper.BindPFlag("log.json", rootCmd.PersistentFlags().Lookup("log.json"))
-	rootCmd.PersistentFlags().StringP("database-url", "d", "host=localhost port=5432", "DSN for database connection")
+	rootCmd.PersistentFlags().StringP("database-url", "d", "host=localhost port=5432", "DSN for database connection, used when no [[databases]] entries are configured")
 	viper.BindPFlag("database.url", rootCmd.PersistentFlags().Lookup("database-url"))
+	rootCmd.PersistentFlags().Duration("database-ping-interval", 30*time.Second, "how often to check connectivity for each configured database")
+	viper.BindPFlag("database.ping_interval", rootCmd.PersistentFlags().Lookup("database-ping-interval"))
 
 	rootCmd.PersistentFlags().Int("port", 2112, "default port to run server on")
 	viper.BindPFlag("server.port", rootCmd.PersistentFlags().Lookup("port"))
+
+	rootCmd.PersistentFlags().String("mode", "pull", "metric delivery mode: pull, push, otlp, all, or a comma-separated combination")
+	viper.BindPFlag("mode", rootCmd.PersistentFlags().Lookup("mode"))
+
+	rootCmd.PersistentFlags().String("push-url", "", "Prometheus Pushgateway URL to push metrics to when mode includes push")
+	viper.BindPFlag("push.url", rootCmd.PersistentFlags().Lookup("push-url"))
+	rootCmd.PersistentFlags().String("push-job", "pvdb_metrics", "job name to report to the Pushgateway")
+	viper.BindPFlag("push.job", rootCmd.PersistentFlags().Lookup("push-job"))
+	rootCmd.PersistentFlags().Duration("push-interval", 30*time.Second, "how often to push metrics to the Pushgateway")
+	viper.BindPFlag("push.interval", rootCmd.PersistentFlags().Lookup("push-interval"))
+
+	rootCmd.PersistentFlags().String("otlp-endpoint", "", "OpenTelemetry collector gRPC endpoint to export metrics to when mode includes otlp")
+	viper.BindPFlag("otlp.endpoint", rootCmd.PersistentFlags().Lookup("otlp-endpoint"))
+
+	rootCmd.PersistentFlags().String("queries-dir", "queries.d", "directory to scan for additional *.toml metric definition files")
+	viper.BindPFlag("queries_dir", rootCmd.PersistentFlags().Lookup("queries-dir"))
+
+	rootCmd.PersistentFlags().String("tls-cert-file", "", "TLS certificate file; serves HTTPS when set along with --tls-key-file")
+	viper.BindPFlag("server.tls.cert_file", rootCmd.PersistentFlags().Lookup("tls-cert-file"))
+	rootCmd.PersistentFlags().String("tls-key-file", "", "TLS private key file; serves HTTPS when set along with --tls-cert-file")
+	viper.BindPFlag("server.tls.key_file", rootCmd.PersistentFlags().Lookup("tls-key-file"))
+
+	rootCmd.PersistentFlags().Duration("shutdown-grace-period", 10*time.Second, "how long to wait for in-flight requests to drain on shutdown")
+	viper.BindPFlag("server.shutdown_grace_period", rootCmd.PersistentFlags().Lookup("shutdown-grace-period"))
 }
 
 func initLog() {
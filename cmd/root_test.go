@@ -0,0 +1,54 @@
+// Copyright 2022
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestEnabledModes(t *testing.T) {
+	tests := []struct {
+		name string
+		mode string
+		want map[string]bool
+	}{
+		{name: "single mode", mode: "pull", want: map[string]bool{modePull: true}},
+		{name: "comma separated", mode: "pull,push", want: map[string]bool{modePull: true, modePush: true}},
+		{name: "case and space insensitive", mode: " PULL , Push ", want: map[string]bool{modePull: true, modePush: true}},
+		{name: "all expands to every mode", mode: "all", want: map[string]bool{modePull: true, modePush: true, modeOtlp: true}},
+		{name: "all combined with an explicit mode", mode: "all,push", want: map[string]bool{modePull: true, modePush: true, modeOtlp: true}},
+		{name: "empty segments are ignored", mode: "pull,,push,", want: map[string]bool{modePull: true, modePush: true}},
+		{name: "empty string yields no modes", mode: "", want: map[string]bool{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viper.Set("mode", tt.mode)
+			defer viper.Set("mode", "")
+
+			got := enabledModes()
+			if len(got) != len(tt.want) {
+				t.Fatalf("enabledModes() = %v, want %v", got, tt.want)
+			}
+			for m := range tt.want {
+				if !got[m] {
+					t.Errorf("enabledModes() = %v, want it to include %q", got, m)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright 2022
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHealthzHandler(t *testing.T) {
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/healthz", nil)
+
+	healthzHandler(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("healthzHandler() status = %d, want %d", rec.Code, http.StatusOK)
+	}
+}
+
+// fakePinger lets tests simulate Ping succeeding or failing without a live
+// Postgres connection.
+type fakePinger struct {
+	err error
+}
+
+func (f fakePinger) Ping(ctx context.Context) error {
+	return f.err
+}
+
+func TestReadyzHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		pools      map[string]pinger
+		wantStatus int
+	}{
+		{
+			name:       "no pools configured",
+			pools:      map[string]pinger{},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "all pools healthy",
+			pools:      map[string]pinger{"default": fakePinger{}},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "a pool fails to ping",
+			pools:      map[string]pinger{"default": fakePinger{err: errors.New("connection refused")}},
+			wantStatus: http.StatusServiceUnavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/readyz", nil)
+
+			readyzHandler(tt.pools)(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("readyzHandler() status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+// Copyright 2022
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rs/zerolog/log"
+)
+
+// defaultCacheTTL is used for any MetricDef that doesn't set cache_ttl.
+const defaultCacheTTL = 30 * time.Second
+
+// CacheAge reports how stale the value served for each metric/database
+// pair is at scrape time, so operators can alert on a cache that's stopped
+// refreshing.
+var CacheAge = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "pvdb_collector_cache_age_seconds",
+		Help: "Age of the cached value last served for a pvdb-metrics collector metric.",
+	},
+	[]string{"metric", "database"},
+)
+
+// ScrapeErrors counts failed background refreshes per metric/database pair.
+var ScrapeErrors = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "pvdb_collector_scrape_errors_total",
+		Help: "Total number of errors refreshing a pvdb-metrics collector metric from Postgres.",
+	},
+	[]string{"metric", "database"},
+)
+
+// cacheEntry holds the last successfully fetched rows for a metric on a
+// given database and when they were fetched.
+type cacheEntry struct {
+	rows      []metricRow
+	fetchedAt time.Time
+}
+
+// cacheKey identifies one metric on one database.
+type cacheKey struct {
+	database string
+	metric   string
+}
+
+// metricCache refreshes each (database, metric) pair asynchronously on the
+// metric's own TTL and serves Collect from whatever was last fetched, so a
+// scrape never blocks on Postgres. This is the same pattern
+// postgres_exporter uses to avoid hammering the database on every scrape.
+type metricCache struct {
+	entries sync.Map // cacheKey -> cacheEntry
+}
+
+// newMetricCache starts one background refresh goroutine per
+// (database, metric) pair and returns immediately; Collect may observe a
+// cache miss until the first refresh of each pair completes. Every
+// goroutine stops once ctx is cancelled, so callers should pass the same
+// context that governs their process's shutdown rather than
+// context.Background().
+func newMetricCache(ctx context.Context, pools map[string]*pgxpool.Pool, defs []MetricDef) *metricCache {
+	c := &metricCache{}
+	for dbName, pool := range pools {
+		for _, def := range defs {
+			go c.refreshLoop(ctx, dbName, pool, def, effectiveCacheTTL(def))
+		}
+	}
+	return c
+}
+
+// effectiveCacheTTL returns def's configured cache_ttl, falling back to
+// defaultCacheTTL when it isn't set (or is non-positive).
+func effectiveCacheTTL(def MetricDef) time.Duration {
+	if def.CacheTTL <= 0 {
+		return defaultCacheTTL
+	}
+	return def.CacheTTL
+}
+
+func (c *metricCache) refreshLoop(ctx context.Context, dbName string, pool *pgxpool.Pool, def MetricDef, ttl time.Duration) {
+	c.refresh(ctx, dbName, pool, def)
+	ticker := time.NewTicker(ttl)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.refresh(ctx, dbName, pool, def)
+		}
+	}
+}
+
+func (c *metricCache) refresh(ctx context.Context, dbName string, pool *pgxpool.Pool, def MetricDef) {
+	rows, err := runMetricQuery(ctx, pool, def)
+	if err != nil {
+		ScrapeErrors.WithLabelValues(def.Name, dbName).Inc()
+		log.Error().Err(err).Str("metric", def.Name).Str("database", dbName).Msg("failed to refresh cached metric")
+		return
+	}
+	c.entries.Store(cacheKey{database: dbName, metric: def.Name}, cacheEntry{rows: rows, fetchedAt: time.Now()})
+}
+
+// get returns the last cached entry for (dbName, metric), if any refresh
+// has succeeded yet.
+func (c *metricCache) get(dbName, metric string) (cacheEntry, bool) {
+	v, ok := c.entries.Load(cacheKey{database: dbName, metric: metric})
+	if !ok {
+		return cacheEntry{}, false
+	}
+	return v.(cacheEntry), true
+}
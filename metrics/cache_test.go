@@ -0,0 +1,92 @@
+// Copyright 2022
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEffectiveCacheTTL(t *testing.T) {
+	tests := []struct {
+		name string
+		def  MetricDef
+		want time.Duration
+	}{
+		{name: "unset falls back to default", def: MetricDef{}, want: defaultCacheTTL},
+		{name: "zero falls back to default", def: MetricDef{CacheTTL: 0}, want: defaultCacheTTL},
+		{name: "negative falls back to default", def: MetricDef{CacheTTL: -time.Second}, want: defaultCacheTTL},
+		{name: "positive value is honored", def: MetricDef{CacheTTL: 5 * time.Minute}, want: 5 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := effectiveCacheTTL(tt.def); got != tt.want {
+				t.Errorf("effectiveCacheTTL(%+v) = %v, want %v", tt.def, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMetricCacheGetMiss(t *testing.T) {
+	c := &metricCache{}
+	if _, ok := c.get("default", "pvdb_missing"); ok {
+		t.Fatal("get() ok = true, want false for an unpopulated cache")
+	}
+}
+
+func TestMetricCacheGetHit(t *testing.T) {
+	c := &metricCache{}
+	fetchedAt := time.Now().Add(-2 * time.Second)
+	c.entries.Store(cacheKey{database: "default", metric: "pvdb_eod_daily"}, cacheEntry{
+		rows:      []metricRow{{value: 42}},
+		fetchedAt: fetchedAt,
+	})
+
+	entry, ok := c.get("default", "pvdb_eod_daily")
+	if !ok {
+		t.Fatal("get() ok = false, want true for a populated entry")
+	}
+	if len(entry.rows) != 1 || entry.rows[0].value != 42 {
+		t.Errorf("get() rows = %+v, want a single row with value 42", entry.rows)
+	}
+	if !entry.fetchedAt.Equal(fetchedAt) {
+		t.Errorf("get() fetchedAt = %v, want %v", entry.fetchedAt, fetchedAt)
+	}
+
+	if age := time.Since(entry.fetchedAt); age < 2*time.Second {
+		t.Errorf("time.Since(fetchedAt) = %v, want at least 2s (this is what feeds CacheAge)", age)
+	}
+}
+
+func TestMetricCacheGetIsolatesDatabasesAndMetrics(t *testing.T) {
+	c := &metricCache{}
+	c.entries.Store(cacheKey{database: "prod", metric: "pvdb_eod_daily"}, cacheEntry{rows: []metricRow{{value: 1}}})
+	c.entries.Store(cacheKey{database: "staging", metric: "pvdb_eod_daily"}, cacheEntry{rows: []metricRow{{value: 2}}})
+
+	prod, ok := c.get("prod", "pvdb_eod_daily")
+	if !ok || prod.rows[0].value != 1 {
+		t.Fatalf("get(prod) = %+v, ok=%v, want value 1", prod, ok)
+	}
+
+	staging, ok := c.get("staging", "pvdb_eod_daily")
+	if !ok || staging.rows[0].value != 2 {
+		t.Fatalf("get(staging) = %+v, ok=%v, want value 2", staging, ok)
+	}
+
+	if _, ok := c.get("prod", "pvdb_assets_new"); ok {
+		t.Fatal("get(prod, pvdb_assets_new) ok = true, want false: no entry was stored under that metric")
+	}
+}
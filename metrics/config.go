@@ -0,0 +1,138 @@
+// Copyright 2022
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// MetricType is the kind of Prometheus metric a MetricDef produces.
+type MetricType string
+
+const (
+	MetricTypeGauge   MetricType = "gauge"
+	MetricTypeCounter MetricType = "counter"
+	// MetricTypeHistogram is reserved for a future collector that can map a
+	// query's rows onto histogram buckets; Validate rejects it until then.
+	MetricTypeHistogram MetricType = "histogram"
+)
+
+// MetricDef is a single metric definition loaded from the TOML config or a
+// file under queries.d/. It mirrors the shape sql_exporter and
+// postgres_exporter use for their collector definitions: a name, help
+// text, a metric type, the label columns the query is expected to return,
+// and the query itself.
+type MetricDef struct {
+	Name     string        `mapstructure:"name"`
+	Help     string        `mapstructure:"help"`
+	Type     MetricType    `mapstructure:"type"`
+	Labels   []string      `mapstructure:"labels"`
+	Query    string        `mapstructure:"query"`
+	Interval time.Duration `mapstructure:"interval"`
+	CacheTTL time.Duration `mapstructure:"cache_ttl"`
+}
+
+// Validate checks that a MetricDef has everything required to build a
+// Prometheus descriptor and run its query.
+func (m MetricDef) Validate() error {
+	if m.Name == "" {
+		return fmt.Errorf("metric definition is missing a name")
+	}
+	if m.Query == "" {
+		return fmt.Errorf("metric %q is missing a query", m.Name)
+	}
+	switch m.Type {
+	case MetricTypeGauge, MetricTypeCounter:
+	case MetricTypeHistogram:
+		return fmt.Errorf("metric %q has type %q, which sqlCollector cannot produce yet: each query row maps to one label/value pair, not histogram buckets", m.Name, m.Type)
+	case "":
+		return fmt.Errorf("metric %q is missing a type", m.Name)
+	default:
+		return fmt.Errorf("metric %q has unknown type %q", m.Name, m.Type)
+	}
+	return nil
+}
+
+// LoadMetricDefs reads metric definitions from the `metrics` array in the
+// viper config, plus any *.toml files found in the queries directory
+// (queries_dir in config, default "queries.d"), analogous to how
+// postgres_exporter loads its collector YAMLs from a directory. Every
+// definition is validated before being returned so a bad config file fails
+// fast at startup rather than at the first scrape. If no definitions are
+// found anywhere, the built-in defaultMetricDefs are returned so upgrading
+// to config-driven definitions doesn't silently blank out a deployment that
+// hasn't migrated its config yet.
+func LoadMetricDefs(v *viper.Viper) ([]MetricDef, error) {
+	var defs []MetricDef
+	if err := v.UnmarshalKey("metrics", &defs); err != nil {
+		return nil, fmt.Errorf("failed to parse metrics config: %w", err)
+	}
+
+	queriesDir := v.GetString("queries_dir")
+	if queriesDir == "" {
+		queriesDir = "queries.d"
+	}
+
+	entries, err := os.ReadDir(queriesDir)
+	if err == nil {
+		for _, entry := range entries {
+			if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".toml") {
+				continue
+			}
+
+			path := filepath.Join(queriesDir, entry.Name())
+			fileDefs, err := loadMetricDefFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("failed to load %s: %w", path, err)
+			}
+			defs = append(defs, fileDefs...)
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to scan %s: %w", queriesDir, err)
+	}
+
+	if len(defs) == 0 {
+		defs = defaultMetricDefs
+	}
+
+	for _, def := range defs {
+		if err := def.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	return defs, nil
+}
+
+func loadMetricDefFile(path string) ([]MetricDef, error) {
+	fv := viper.New()
+	fv.SetConfigFile(path)
+	fv.SetConfigType("toml")
+	if err := fv.ReadInConfig(); err != nil {
+		return nil, err
+	}
+
+	var defs []MetricDef
+	if err := fv.UnmarshalKey("metrics", &defs); err != nil {
+		return nil, err
+	}
+	return defs, nil
+}
@@ -0,0 +1,164 @@
+// Copyright 2022
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestMetricDefValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		def     MetricDef
+		wantErr bool
+	}{
+		{
+			name: "valid gauge",
+			def:  MetricDef{Name: "pvdb_x", Type: MetricTypeGauge, Query: "SELECT 1"},
+		},
+		{
+			name:    "missing name",
+			def:     MetricDef{Type: MetricTypeGauge, Query: "SELECT 1"},
+			wantErr: true,
+		},
+		{
+			name:    "missing query",
+			def:     MetricDef{Name: "pvdb_x", Type: MetricTypeGauge},
+			wantErr: true,
+		},
+		{
+			name:    "missing type",
+			def:     MetricDef{Name: "pvdb_x", Query: "SELECT 1"},
+			wantErr: true,
+		},
+		{
+			name:    "unknown type",
+			def:     MetricDef{Name: "pvdb_x", Type: "summary", Query: "SELECT 1"},
+			wantErr: true,
+		},
+		{
+			name:    "histogram not yet supported",
+			def:     MetricDef{Name: "pvdb_x", Type: MetricTypeHistogram, Query: "SELECT 1"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.def.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadMetricDefsDefaultsWhenUnconfigured(t *testing.T) {
+	v := viper.New()
+	v.Set("queries_dir", filepath.Join(t.TempDir(), "does-not-exist"))
+
+	defs, err := LoadMetricDefs(v)
+	if err != nil {
+		t.Fatalf("LoadMetricDefs() error = %v", err)
+	}
+	if len(defs) != len(defaultMetricDefs) {
+		t.Fatalf("got %d defs, want the %d built-in defaults", len(defs), len(defaultMetricDefs))
+	}
+}
+
+func TestLoadMetricDefsFromConfig(t *testing.T) {
+	v := viper.New()
+	v.Set("queries_dir", filepath.Join(t.TempDir(), "does-not-exist"))
+	v.Set("metrics", []map[string]interface{}{
+		{
+			"name":  "pvdb_custom_total",
+			"help":  "a custom metric",
+			"type":  "counter",
+			"query": "SELECT count(*) FROM widgets",
+		},
+	})
+
+	defs, err := LoadMetricDefs(v)
+	if err != nil {
+		t.Fatalf("LoadMetricDefs() error = %v", err)
+	}
+	if len(defs) != 1 || defs[0].Name != "pvdb_custom_total" {
+		t.Fatalf("got %+v, want a single pvdb_custom_total def", defs)
+	}
+}
+
+func TestLoadMetricDefsRejectsInvalidConfig(t *testing.T) {
+	v := viper.New()
+	v.Set("queries_dir", filepath.Join(t.TempDir(), "does-not-exist"))
+	v.Set("metrics", []map[string]interface{}{
+		{
+			"name": "pvdb_missing_query",
+			"type": "gauge",
+		},
+	})
+
+	if _, err := LoadMetricDefs(v); err == nil {
+		t.Fatal("LoadMetricDefs() error = nil, want an error for a metric with no query")
+	}
+}
+
+func TestLoadMetricDefsScansQueriesDir(t *testing.T) {
+	dir := t.TempDir()
+	toml := `
+[[metrics]]
+name = "pvdb_from_file"
+help = "loaded from queries.d"
+type = "gauge"
+query = "SELECT count(*) FROM eod"
+`
+	if err := os.WriteFile(filepath.Join(dir, "eod.toml"), []byte(toml), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	v := viper.New()
+	v.Set("queries_dir", dir)
+
+	defs, err := LoadMetricDefs(v)
+	if err != nil {
+		t.Fatalf("LoadMetricDefs() error = %v", err)
+	}
+	if len(defs) != 1 || defs[0].Name != "pvdb_from_file" {
+		t.Fatalf("got %+v, want a single pvdb_from_file def loaded from queries.d", defs)
+	}
+}
+
+func TestLoadMetricDefsRejectsInvalidQueriesDirFile(t *testing.T) {
+	dir := t.TempDir()
+	toml := `
+[[metrics]]
+name = "pvdb_bad_type"
+type = "summary"
+query = "SELECT 1"
+`
+	if err := os.WriteFile(filepath.Join(dir, "bad.toml"), []byte(toml), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	v := viper.New()
+	v.Set("queries_dir", dir)
+
+	if _, err := LoadMetricDefs(v); err == nil {
+		t.Fatal("LoadMetricDefs() error = nil, want an error for an unknown metric type in queries.d")
+	}
+}
@@ -0,0 +1,131 @@
+// Copyright 2022
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/spf13/viper"
+)
+
+// DatabaseConfig describes one entry in the `[[databases]]` array of the
+// TOML config: a named Postgres connection pvdb-metrics scrapes and labels
+// its metrics with.
+type DatabaseConfig struct {
+	Name             string        `mapstructure:"name"`
+	URL              string        `mapstructure:"url"`
+	MinConns         int32         `mapstructure:"min_conns"`
+	MaxConns         int32         `mapstructure:"max_conns"`
+	StatementTimeout time.Duration `mapstructure:"statement_timeout"`
+}
+
+// DatabaseUp reports whether the last connectivity check against a
+// configured database succeeded.
+var DatabaseUp = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "pvdb_database_up",
+		Help: "Whether the last connectivity check against a configured database succeeded (1) or not (0).",
+	},
+	[]string{"database"},
+)
+
+// LoadDatabaseConfigs reads the `databases` array from the viper config. If
+// none are configured, a single "default" database is synthesized from the
+// legacy database.url flag so existing single-database deployments keep
+// working unchanged.
+func LoadDatabaseConfigs(v *viper.Viper) ([]DatabaseConfig, error) {
+	var dbs []DatabaseConfig
+	if err := v.UnmarshalKey("databases", &dbs); err != nil {
+		return nil, fmt.Errorf("failed to parse databases config: %w", err)
+	}
+
+	if len(dbs) == 0 {
+		if url := v.GetString("database.url"); url != "" {
+			dbs = append(dbs, DatabaseConfig{Name: "default", URL: url})
+		}
+	}
+
+	for _, db := range dbs {
+		if db.Name == "" {
+			return nil, fmt.Errorf("database config is missing a name")
+		}
+		if db.URL == "" {
+			return nil, fmt.Errorf("database %q is missing a url", db.Name)
+		}
+	}
+
+	return dbs, nil
+}
+
+// Connect opens a pgxpool.Pool for db, applying its connection pool and
+// statement timeout settings.
+func Connect(ctx context.Context, db DatabaseConfig) (*pgxpool.Pool, error) {
+	cfg, err := pgxpool.ParseConfig(db.URL)
+	if err != nil {
+		return nil, fmt.Errorf("database %q: %w", db.Name, err)
+	}
+
+	if db.MinConns > 0 {
+		cfg.MinConns = db.MinConns
+	}
+	if db.MaxConns > 0 {
+		cfg.MaxConns = db.MaxConns
+	}
+	if db.StatementTimeout > 0 {
+		cfg.ConnConfig.RuntimeParams["statement_timeout"] = fmt.Sprintf("%d", db.StatementTimeout.Milliseconds())
+	}
+
+	return pgxpool.ConnectConfig(ctx, cfg)
+}
+
+// StartLivenessLoop periodically pings every pool and updates DatabaseUp,
+// until ctx is cancelled.
+func StartLivenessLoop(ctx context.Context, pools map[string]*pgxpool.Pool, interval time.Duration) {
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+
+	ping := func() {
+		for name, pool := range pools {
+			pingCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+			err := pool.Ping(pingCtx)
+			cancel()
+
+			if err != nil {
+				DatabaseUp.WithLabelValues(name).Set(0)
+				continue
+			}
+			DatabaseUp.WithLabelValues(name).Set(1)
+		}
+	}
+
+	ping()
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				ping()
+			}
+		}
+	}()
+}
@@ -0,0 +1,89 @@
+// Copyright 2022
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestLoadDatabaseConfigsSynthesizesDefault(t *testing.T) {
+	v := viper.New()
+	v.Set("database.url", "host=localhost port=5432")
+
+	dbs, err := LoadDatabaseConfigs(v)
+	if err != nil {
+		t.Fatalf("LoadDatabaseConfigs() error = %v", err)
+	}
+	if len(dbs) != 1 || dbs[0].Name != "default" || dbs[0].URL != "host=localhost port=5432" {
+		t.Fatalf("got %+v, want a single synthesized \"default\" database", dbs)
+	}
+}
+
+func TestLoadDatabaseConfigsNoneConfigured(t *testing.T) {
+	v := viper.New()
+
+	dbs, err := LoadDatabaseConfigs(v)
+	if err != nil {
+		t.Fatalf("LoadDatabaseConfigs() error = %v", err)
+	}
+	if len(dbs) != 0 {
+		t.Fatalf("got %+v, want no databases when neither databases nor database.url is set", dbs)
+	}
+}
+
+func TestLoadDatabaseConfigsFromArray(t *testing.T) {
+	v := viper.New()
+	v.Set("databases", []map[string]interface{}{
+		{"name": "prod", "url": "host=prod port=5432", "min_conns": 2, "max_conns": 10},
+		{"name": "staging", "url": "host=staging port=5432"},
+	})
+
+	dbs, err := LoadDatabaseConfigs(v)
+	if err != nil {
+		t.Fatalf("LoadDatabaseConfigs() error = %v", err)
+	}
+	if len(dbs) != 2 {
+		t.Fatalf("got %d databases, want 2", len(dbs))
+	}
+	if dbs[0].Name != "prod" || dbs[0].MinConns != 2 || dbs[0].MaxConns != 10 {
+		t.Errorf("got %+v, want prod with min_conns=2, max_conns=10", dbs[0])
+	}
+	if dbs[1].Name != "staging" || dbs[1].URL != "host=staging port=5432" {
+		t.Errorf("got %+v, want staging database", dbs[1])
+	}
+}
+
+func TestLoadDatabaseConfigsRejectsMissingFields(t *testing.T) {
+	tests := []struct {
+		name string
+		db   map[string]interface{}
+	}{
+		{name: "missing name", db: map[string]interface{}{"url": "host=x"}},
+		{name: "missing url", db: map[string]interface{}{"name": "x"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := viper.New()
+			v.Set("databases", []map[string]interface{}{tt.db})
+
+			if _, err := LoadDatabaseConfigs(v); err == nil {
+				t.Fatal("LoadDatabaseConfigs() error = nil, want an error")
+			}
+		})
+	}
+}
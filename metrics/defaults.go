@@ -0,0 +1,75 @@
+// Copyright 2022
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+// defaultMetricDefs mirrors the metrics that used to be hard-coded in this
+// package, back when each table had its own Collector. They're used
+// whenever the config has no [[metrics]] entries and queries.d/ is empty.
+var defaultMetricDefs = []MetricDef{
+	{
+		Name:  "pvdb_eod_daily",
+		Help:  "Number of EOD quotes downloaded today",
+		Type:  MetricTypeGauge,
+		Query: "SELECT count(*) AS cnt FROM eod WHERE event_date::date = (now() - '1 day'::interval)::date",
+	},
+	{
+		Name:  "pvdb_eod_no_figi",
+		Help:  "Number of EOD quotes downloaded today",
+		Type:  MetricTypeGauge,
+		Query: "SELECT count(*) AS cnt FROM eod WHERE composite_figi=''",
+	},
+	{
+		Name:  "pvdb_assets_new",
+		Help:  "Number of new assets in the last 24 hours",
+		Type:  MetricTypeGauge,
+		Query: "SELECT count(*) AS cnt FROM assets WHERE new = True",
+	},
+	{
+		Name:  "pvdb_assets_changed",
+		Help:  "Number of changed assets in the last 24 hours",
+		Type:  MetricTypeGauge,
+		Query: "SELECT count(*) AS cnt FROM assets WHERE updated = True",
+	},
+	{
+		Name:  "pvdb_assets_retired",
+		Help:  "Number of retired assets in the last 24 hours",
+		Type:  MetricTypeGauge,
+		Query: "SELECT count(*) AS cnt FROM assets WHERE active = False AND updated = True",
+	},
+	{
+		Name:  "pvdb_assets_no_cusip",
+		Help:  "Number of assets with no CUSIP",
+		Type:  MetricTypeGauge,
+		Query: "SELECT count(*) AS cnt FROM assets WHERE cusip = ''",
+	},
+	{
+		Name:  "pvdb_assets_no_figi",
+		Help:  "Number of assets with no Composite FIGI",
+		Type:  MetricTypeGauge,
+		Query: "SELECT count(*) AS cnt FROM eod WHERE event_date::date = (now() - '1 day'::interval)::date",
+	},
+	{
+		Name:  "pvdb_seeking_alpha_daily",
+		Help:  "Number of Seeking Alpha ratings in last 24 hours",
+		Type:  MetricTypeGauge,
+		Query: "SELECT count(*) AS cnt FROM seeking_alpha WHERE event_date::date = now()::date",
+	},
+	{
+		Name:  "pvdb_zacks_finance_daily",
+		Help:  "Number of Zacks Finance records in last 24 hours",
+		Type:  MetricTypeGauge,
+		Query: "SELECT count(*) AS cnt FROM zacks_financials WHERE event_date::date = now()::date",
+	},
+}
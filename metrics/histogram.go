@@ -0,0 +1,33 @@
+// Copyright 2022
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// QueryDuration records how long each SQL query backing a collector takes
+// to execute, as a Prometheus native (sparse) histogram keyed by the metric
+// it feeds. Unlike the metrics sqlCollector produces on demand in Collect,
+// it carries state across scrapes, so it is registered directly against the
+// registry in cmd/root.go.
+var QueryDuration = prometheus.NewHistogramVec(
+	prometheus.HistogramOpts{
+		Name: "pvdb_collector_query_duration_seconds",
+		Help: "Execution time of the SQL query backing a pvdb-metrics collector.",
+
+		NativeHistogramBucketFactor:    1.1,
+		NativeHistogramMaxBucketNumber: 160,
+	},
+	[]string{"metric"},
+)
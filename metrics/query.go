@@ -0,0 +1,60 @@
+// Copyright 2022
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// queryTimeout bounds how long a single collector query is allowed to run.
+const queryTimeout = 10 * time.Second
+
+// tracerProvider is a private SDK provider, not registered with the global
+// otel package, so it keeps producing real random trace/span IDs for
+// exemplars regardless of whether the process has a TracerProvider
+// installed elsewhere. Going through otel.Tracer() here instead would
+// silently hand back the API package's no-op tracer whenever nothing has
+// called otel.SetTracerProvider, and every exemplar would carry an
+// all-zero trace ID.
+var tracerProvider = sdktrace.NewTracerProvider()
+var tracer = tracerProvider.Tracer("github.com/penny-vault/pvdb-metrics/metrics")
+
+// startQuerySpan opens a bounded context and span for a single collector
+// query; the span's trace ID becomes the OpenMetrics exemplar attached to
+// the duration observation recorded via observeQueryDuration. Callers must
+// defer both the returned cancel func and span.End().
+func startQuerySpan(ctx context.Context, metricName string) (context.Context, trace.Span, context.CancelFunc) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	ctx, span := tracer.Start(ctx, metricName)
+	return ctx, span, cancel
+}
+
+// observeQueryDuration records how long a query took against QueryDuration,
+// attaching span's trace ID as an OpenMetrics exemplar where supported.
+func observeQueryDuration(span trace.Span, metricName string, elapsed time.Duration) {
+	obs := QueryDuration.WithLabelValues(metricName)
+	if exemplarObs, ok := obs.(prometheus.ExemplarObserver); ok {
+		exemplarObs.ObserveWithExemplar(elapsed.Seconds(), prometheus.Labels{
+			"trace_id": span.SpanContext().TraceID().String(),
+		})
+	} else {
+		obs.Observe(elapsed.Seconds())
+	}
+}
@@ -0,0 +1,159 @@
+// Copyright 2022
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// sqlCollector is a generic, config-driven Collector: each MetricDef
+// becomes one Prometheus descriptor, scraped against every configured
+// database and labeled with which one it came from. Samples are served
+// from a metricCache that refreshes in the background, so a scrape never
+// blocks on Postgres.
+type sqlCollector struct {
+	defs    []MetricDef
+	descs   []*prometheus.Desc
+	dbNames []string
+	cache   *metricCache
+}
+
+func newSQLCollector(ctx context.Context, pools map[string]*pgxpool.Pool, defs []MetricDef) prometheus.Collector {
+	descs := make([]*prometheus.Desc, len(defs))
+	for i, def := range defs {
+		labels := append([]string{"database"}, def.Labels...)
+		descs[i] = prometheus.NewDesc(def.Name, def.Help, labels, nil)
+	}
+
+	dbNames := make([]string, 0, len(pools))
+	for name := range pools {
+		dbNames = append(dbNames, name)
+	}
+	sort.Strings(dbNames)
+
+	return &sqlCollector{
+		defs:    defs,
+		descs:   descs,
+		dbNames: dbNames,
+		cache:   newMetricCache(ctx, pools, defs),
+	}
+}
+
+// Describe implements Collector.
+func (c *sqlCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range c.descs {
+		ch <- d
+	}
+}
+
+// Collect implements Collector. It never touches Postgres directly; it
+// serves whatever the background cache refresh last fetched for each
+// database.
+func (c *sqlCollector) Collect(ch chan<- prometheus.Metric) {
+	for i, def := range c.defs {
+		valueType := prometheus.GaugeValue
+		if def.Type == MetricTypeCounter {
+			valueType = prometheus.CounterValue
+		}
+
+		for _, dbName := range c.dbNames {
+			entry, ok := c.cache.get(dbName, def.Name)
+			if !ok {
+				continue
+			}
+
+			CacheAge.WithLabelValues(def.Name, dbName).Set(time.Since(entry.fetchedAt).Seconds())
+
+			for _, row := range entry.rows {
+				labelValues := append([]string{dbName}, row.labels...)
+				ch <- prometheus.MustNewConstMetric(c.descs[i], valueType, row.value, labelValues...)
+			}
+		}
+	}
+}
+
+type metricRow struct {
+	labels []string
+	value  float64
+}
+
+// runMetricQuery executes def's query against pool, recording its
+// execution time against QueryDuration, and maps each returned row's label
+// columns plus trailing value column into a metricRow.
+func runMetricQuery(ctx context.Context, pool *pgxpool.Pool, def MetricDef) ([]metricRow, error) {
+	ctx, span, cancel := startQuerySpan(ctx, def.Name)
+	defer cancel()
+	defer span.End()
+
+	start := time.Now()
+	pgRows, err := pool.Query(ctx, def.Query)
+	if err != nil {
+		return nil, err
+	}
+	defer pgRows.Close()
+
+	var rows []metricRow
+	for pgRows.Next() {
+		vals, err := pgRows.Values()
+		if err != nil {
+			return nil, err
+		}
+		if len(vals) != len(def.Labels)+1 {
+			return nil, fmt.Errorf("metric %q query returned %d columns, expected %d label(s) + 1 value", def.Name, len(vals), len(def.Labels))
+		}
+
+		labels := make([]string, len(def.Labels))
+		for i := range def.Labels {
+			labels[i] = fmt.Sprintf("%v", vals[i])
+		}
+
+		value, err := toFloat64(vals[len(vals)-1])
+		if err != nil {
+			return nil, fmt.Errorf("metric %q: %w", def.Name, err)
+		}
+
+		rows = append(rows, metricRow{labels: labels, value: value})
+	}
+	if err := pgRows.Err(); err != nil {
+		return nil, err
+	}
+
+	observeQueryDuration(span, def.Name, time.Since(start))
+
+	return rows, nil
+}
+
+func toFloat64(v interface{}) (float64, error) {
+	switch n := v.(type) {
+	case float64:
+		return n, nil
+	case float32:
+		return float64(n), nil
+	case int64:
+		return float64(n), nil
+	case int32:
+		return float64(n), nil
+	case int:
+		return float64(n), nil
+	default:
+		return 0, fmt.Errorf("value column has unsupported type %T", v)
+	}
+}
@@ -0,0 +1,46 @@
+// Copyright 2022
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import "testing"
+
+func TestToFloat64(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      interface{}
+		want    float64
+		wantErr bool
+	}{
+		{name: "float64", in: float64(1.5), want: 1.5},
+		{name: "float32", in: float32(2.5), want: 2.5},
+		{name: "int64", in: int64(42), want: 42},
+		{name: "int32", in: int32(7), want: 7},
+		{name: "int", in: 3, want: 3},
+		{name: "unsupported string", in: "not a number", wantErr: true},
+		{name: "unsupported nil", in: nil, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := toFloat64(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("toFloat64(%v) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("toFloat64(%v) = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+}